@@ -19,8 +19,8 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
+	"sync"
 	"time"
-	"net"
 
 	"github.com/coreos/go-systemd/sdjournal"
 	"github.com/elastic/beats/libbeat/beat"
@@ -29,8 +29,8 @@ import (
 	"github.com/elastic/beats/libbeat/publisher"
 	"github.com/medallia/journalbeat/config"
 	"github.com/medallia/journalbeat/journal"
-	"github.com/wavefronthq/go-metrics-wavefront"
-	"github.com/rcrowley/go-metrics"
+	"github.com/medallia/journalbeat/metrics"
+	"github.com/medallia/journalbeat/output"
 )
 
 type LogBuffer struct {
@@ -65,21 +65,43 @@ type Journalbeat struct {
 	config config.Config
 	client publisher.Client
 
+	// wg covers every goroutine that must exit before Run can return,
+	// so Stop can deterministically wait for the pipeline to drain.
+	wg              sync.WaitGroup
+	closeClientOnce sync.Once
+
 	journal *sdjournal.Journal
 
+	// journalRemote, when configured, forwards every journal entry to a
+	// systemd-journal-remote endpoint in the Journal Export Format.
+	journalRemote *output.JournalRemote
+
 	cursorChan chan string
 
 	journalTypeOutstandingLogBuffer map[string]*LogBuffer
 	incomingLogMessages             chan common.MapStr
 
-	logMessagesPublished metrics.Counter
-	logMessageDelay      metrics.Gauge
+	// metrics is the active backend (Wavefront or Prometheus), selected
+	// by config.Metrics.Backend; it is nil when metrics are disabled.
+	metrics metrics.Sink
+
+	logMessagesPublished       metrics.Counter
+	logMessagesPublishedByType metrics.CounterVec
+	logMessageDelay            metrics.Gauge
+	bufferedEntries            metrics.Gauge
+	cursorWriteLatency         metrics.Histogram
+	publishErrors              metrics.Counter
+
+	// journalRestarts and journalLastErrorSeconds track the journal
+	// follow loop's auto-restart supervisor.
+	journalRestarts         metrics.Counter
+	journalLastErrorSeconds metrics.Gauge
 }
 
 func (jb *Journalbeat) initJournal() error {
 	var err error
 
-	seekToHelper := func(position string, err error) error {
+	seekToHelper := func(position config.SeekMode, err error) error {
 		if err == nil {
 			logp.Info("Seek to %s successful", position)
 		} else {
@@ -93,27 +115,24 @@ func (jb *Journalbeat) initJournal() error {
 		return err
 	}
 
-	// add specific units to monitor if any
-	for _, unit := range jb.config.Units {
-		if err = jb.journal.AddMatch(sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT + "=" + unit); err != nil {
-			return fmt.Errorf("Filtering unit %s failed: %v", unit, err)
-		}
+	if err = jb.applyMatches(jb.journal); err != nil {
+		return err
 	}
 
 	// seek position
 	position := jb.config.SeekPosition
 	// try seekToCursor first, if that is requested
-	if position == config.SeekPositionCursor {
+	if position == config.SeekModeCursor {
 		if cursor, err := ioutil.ReadFile(jb.config.CursorStateFile); err != nil {
 			logp.Warn("Could not seek to cursor: reading cursor state file failed: %v", err)
 		} else {
 			// try to seek to cursor and if successful return
-			if err = seekToHelper(config.SeekPositionCursor, jb.journal.SeekCursor(string(cursor))); err == nil {
+			if err = seekToHelper(config.SeekModeCursor, jb.journal.SeekCursor(string(cursor))); err == nil {
 				return nil
 			}
 		}
 
-		if jb.config.CursorSeekFallback == config.SeekPositionDefault {
+		if jb.config.CursorSeekFallback == config.SeekModeDefault {
 			return err
 		}
 
@@ -121,10 +140,20 @@ func (jb *Journalbeat) initJournal() error {
 	}
 
 	switch position {
-	case config.SeekPositionHead:
-		err = seekToHelper(config.SeekPositionHead, jb.journal.SeekHead())
-	case config.SeekPositionTail:
-		err = seekToHelper(config.SeekPositionTail, jb.journal.SeekTail())
+	case config.SeekModeHead:
+		err = seekToHelper(config.SeekModeHead, jb.journal.SeekHead())
+	case config.SeekModeTail:
+		if err = seekToHelper(config.SeekModeTail, jb.journal.SeekTail()); err == nil {
+			// SeekTail() places the read pointer just past the last entry, but
+			// that entry is still returned by the next Next()/Follow() call.
+			// Skip over it so the last already-persisted entry isn't
+			// re-published every time Journalbeat restarts on a quiet host.
+			if _, nextErr := jb.journal.Next(); nextErr != nil {
+				logp.Warn("Could not skip last entry after seeking to tail: %v", nextErr)
+			}
+		}
+	case config.SeekModeSince:
+		err = seekToHelper(config.SeekModeSince, jb.seekSince(jb.config.SeekSince))
 	}
 
 	if err != nil {
@@ -134,13 +163,219 @@ func (jb *Journalbeat) initJournal() error {
 	return nil
 }
 
+// journalMatcher is the subset of *sdjournal.Journal that applyMatches
+// and its helpers depend on. Narrowing to this interface lets the
+// match-sequence construction below be exercised with a fake in tests,
+// without a live journald. *sdjournal.Journal satisfies it.
+type journalMatcher interface {
+	AddMatch(match string) error
+	AddDisjunction() error
+	AddConjunction() error
+}
+
+// applyMatches applies the configured filter DSL to j in a single pass,
+// then the legacy Units shortcut and filter.include_units. It is shared
+// by initJournal and reopenJournal so a restarted journal is filtered
+// the same way as the original one.
+//
+// sd-journal's match list is a flat sequence: AddDisjunction() ORs the
+// terms that follow with the terms before it, and AddConjunction() ANDs
+// everything that follows with the whole OR expression built so far.
+// Disjunctions, priority and each Conjunctions block are independent
+// filters that must all be satisfied, so each is closed off with its own
+// AddConjunction() before the next one starts -- none of them may be
+// allowed to bleed into another's OR set. The plain AND terms -- units,
+// identifiers and matches -- are appended last so they apply on top of
+// all of the above.
+func (jb *Journalbeat) applyMatches(j journalMatcher) error {
+	f := jb.config.Filter
+
+	if err := addDisjunctionOfGroups(j, f.Disjunctions); err != nil {
+		return err
+	}
+
+	priorityGroup, err := f.priorityGroup()
+	if err != nil {
+		return err
+	}
+	if priorityGroup != nil {
+		if err := addOrBlock(j, priorityGroup); err != nil {
+			return err
+		}
+	}
+
+	for _, block := range f.Conjunctions {
+		if err := addOrBlock(j, block); err != nil {
+			return err
+		}
+	}
+
+	units := append(append([]string{}, jb.config.Units...), f.IncludeUnits...)
+	for _, unit := range units {
+		if err := j.AddMatch(sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT + "=" + unit); err != nil {
+			return fmt.Errorf("Filtering unit %s failed: %v", unit, err)
+		}
+	}
+
+	for _, id := range f.Identifiers {
+		if err := j.AddMatch(tagField + "=" + id); err != nil {
+			return fmt.Errorf("Filtering identifier %s failed: %v", id, err)
+		}
+	}
+
+	for _, m := range f.Matches {
+		if err := j.AddMatch(m); err != nil {
+			return fmt.Errorf("Applying match %q failed: %v", m, err)
+		}
+	}
+
+	return nil
+}
+
+// addDisjunctionOfGroups ORs together a list of AND-groups -- each
+// group's terms are AND'd with each other, and the groups themselves are
+// OR'd via AddDisjunction -- then closes the whole expression with a
+// single AddConjunction so it ANDs with whatever filters follow.
+func addDisjunctionOfGroups(j journalMatcher, groups [][]string) error {
+	for i, group := range groups {
+		for _, term := range group {
+			if err := j.AddMatch(term); err != nil {
+				return fmt.Errorf("applying filter term %q failed: %v", term, err)
+			}
+		}
+		if i < len(groups)-1 {
+			if err := j.AddDisjunction(); err != nil {
+				return fmt.Errorf("starting filter disjunction failed: %v", err)
+			}
+		}
+	}
+	if len(groups) > 0 {
+		if err := j.AddConjunction(); err != nil {
+			return fmt.Errorf("closing filter disjunction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// addOrBlock ORs together a single list of terms -- via AddDisjunction
+// between each pair -- then closes it with AddConjunction so it ANDs
+// with whatever filters follow. It's used for the priority shortcut and
+// each filter.conjunctions entry, both of which are standalone OR-blocks
+// rather than Disjunctions' OR-of-AND-groups.
+func addOrBlock(j journalMatcher, terms []string) error {
+	for i, term := range terms {
+		if err := j.AddMatch(term); err != nil {
+			return fmt.Errorf("applying filter term %q failed: %v", term, err)
+		}
+		if i < len(terms)-1 {
+			if err := j.AddDisjunction(); err != nil {
+				return fmt.Errorf("starting filter disjunction failed: %v", err)
+			}
+		}
+	}
+	if len(terms) > 0 {
+		if err := j.AddConjunction(); err != nil {
+			return fmt.Errorf("closing filter disjunction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// excludedUnit reports whether rawEvent's _SYSTEMD_UNIT is in
+// filter.exclude_units. sd-journal has no negative match, so exclusion
+// is applied as a post-filter here rather than in applyMatches.
+func (jb *Journalbeat) excludedUnit(unit string) bool {
+	for _, excluded := range jb.config.Filter.ExcludeUnits {
+		if unit == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// reopenJournal is used by the follow-loop supervisor to recover after a
+// non-cancellation sdjournal error: it opens a fresh journal, reapplies
+// the configured matches and seeks to the last cursor persisted to
+// CursorStateFile, so the restarted follow loop resumes as close as
+// possible to where it left off.
+func (jb *Journalbeat) reopenJournal() (*sdjournal.Journal, error) {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = jb.applyMatches(j); err != nil {
+		j.Close()
+		return nil, err
+	}
+
+	cursor, err := ioutil.ReadFile(jb.config.CursorStateFile)
+	if err != nil {
+		j.Close()
+		return nil, fmt.Errorf("reading cursor state file failed: %v", err)
+	}
+
+	if err = j.SeekCursor(string(cursor)); err != nil {
+		j.Close()
+		return nil, fmt.Errorf("seeking to last cursor failed: %v", err)
+	}
+
+	jb.journal = j
+	return j, nil
+}
+
+// onJournalRestart records restart metrics and emits a synthetic
+// sentinel event so downstream consumers can detect the coverage gap
+// caused by the restart.
+func (jb *Journalbeat) onJournalRestart(cause error) {
+	if jb.journalRestarts != nil {
+		jb.journalRestarts.Inc(1)
+	}
+	if jb.journalLastErrorSeconds != nil {
+		jb.journalLastErrorSeconds.Update(time.Now().Unix())
+	}
+
+	now := time.Now()
+	jb.incomingLogMessages <- common.MapStr{
+		"input_type":       jb.config.DefaultType,
+		"type":             "journalbeat",
+		"logBufferingType": "journalbeat-restart",
+		"message":          fmt.Sprintf("journalbeat: journal follow loop restarted after error: %v", cause),
+		"cursor":           "",
+		"@timestamp":       jb.convertMicrosecondsEpochToISO8601(now.UnixNano() / microsToNanos),
+		"utcTimestamp":     now.UnixNano() / microsToNanos,
+	}
+}
+
+// seekSince seeks the journal to an absolute or relative timestamp. Relative
+// timestamps are given as a negative Go duration (e.g. "-1h") and are
+// resolved against time.Now(); anything else is parsed as an absolute
+// RFC3339 timestamp.
+func (jb *Journalbeat) seekSince(since string) error {
+	var target time.Time
+
+	if d, err := time.ParseDuration(since); err == nil {
+		target = time.Now().Add(d)
+	} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+		target = t
+	} else {
+		return fmt.Errorf("seek_since %q is neither a relative duration (e.g. -1h) nor an RFC3339 timestamp", since)
+	}
+
+	usec := uint64(target.UnixNano() / int64(microsToNanos))
+	return jb.journal.SeekRealtimeUsec(usec)
+}
+
 // WriteCursorLoop runs the loop which flushes the current cursor position to a file
 func (jb *Journalbeat) writeCursorLoop() {
 	var cursor string
 	saveCursorState := func(cursor string) {
 		if cursor != "" {
+			start := time.Now()
 			if err := ioutil.WriteFile(jb.config.CursorStateFile, []byte(cursor), 0644); err != nil {
 				logp.Err("Could not write to cursor state file: %v", err)
+			} else if jb.metrics != nil {
+				jb.cursorWriteLatency.Update(time.Since(start).Nanoseconds() / microsToNanos)
 			}
 		}
 	}
@@ -182,6 +417,13 @@ func New(b *beat.Beat, cfg *common.Config) (beat.Beater, error) {
 		return nil, err
 	}
 
+	if config.Output.JournalRemote.URL != "" {
+		if jb.journalRemote, err = output.NewJournalRemote(config.Output.JournalRemote); err != nil {
+			logp.Err("Failed to set up journal-remote output: %v", err)
+			return nil, err
+		}
+	}
+
 	return jb, nil
 }
 
@@ -189,13 +431,54 @@ func (jb *Journalbeat) flushStaleLogMessages() {
 	for logType, logBuffer := range jb.journalTypeOutstandingLogBuffer {
 		if time.Now().Sub(logBuffer.time).Seconds() >= jb.config.FlushLogInterval.Seconds() {
 			//this message has been sitting in our buffer for more than 30 seconds time to flush it.
-			jb.client.PublishEvent(logBuffer.logEvent, publisher.Guaranteed)
+			jb.publishEvent(logBuffer.logEvent)
 			delete(jb.journalTypeOutstandingLogBuffer, logType)
+			// writeCursorLoop, the only reader of cursorChan, only runs
+			// when write_cursor_state is enabled -- sending here
+			// unconditionally would block forever on an unbuffered
+			// channel with nobody to receive it.
+			if jb.config.WriteCursorState {
+				jb.cursorChan <- logBuffer.logEvent["cursor"].(string)
+			}
+		}
+	}
+}
+
+// flushAllLogMessages flushes every buffered multi-line entry regardless
+// of age. It is used during shutdown so nothing in flight is lost while
+// draining the pipeline.
+func (jb *Journalbeat) flushAllLogMessages() {
+	for logType, logBuffer := range jb.journalTypeOutstandingLogBuffer {
+		jb.publishEvent(logBuffer.logEvent)
+		delete(jb.journalTypeOutstandingLogBuffer, logType)
+		if jb.config.WriteCursorState {
 			jb.cursorChan <- logBuffer.logEvent["cursor"].(string)
 		}
 	}
 }
 
+// publishEvent sends event to the Elastic publisher and, when a metrics
+// Sink is active, records whether it succeeded, which logBufferingType
+// it belonged to, and how stale it was by the time it was published.
+func (jb *Journalbeat) publishEvent(event common.MapStr) {
+	ok := jb.client.PublishEvent(event, publisher.Guaranteed)
+
+	if jb.metrics == nil {
+		return
+	}
+
+	if !ok {
+		jb.publishErrors.Inc(1)
+		return
+	}
+
+	jb.logMessagesPublished.Inc(1)
+	jb.logMessagesPublishedByType.WithLabel(event["logBufferingType"].(string)).Inc(1)
+	if utcTimestamp, ok := event["utcTimestamp"].(int64); ok {
+		jb.logMessageDelay.Update(time.Now().Unix() - utcTimestamp/microseconds)
+	}
+}
+
 func (jb *Journalbeat) flushOrBufferLogs(event common.MapStr) {
 	//check if it starts with space or tab
 	newLogMessage := event["message"].(string)
@@ -223,28 +506,39 @@ func (jb *Journalbeat) flushOrBufferLogs(event common.MapStr) {
 		}
 		if found {
 			//flush the older logs to async.
-			jb.client.PublishEvent(oldLogBuffer.logEvent, publisher.Guaranteed)
-			//update stats if enabled
-			if jb.config.MetricsEnabled {
-				jb.logMessagesPublished.Inc(1)
-				jb.logMessageDelay.Update(time.Now().Unix() - (event["utcTimestamp"].(int64) / microseconds))
-			}
+			jb.publishEvent(oldLogBuffer.logEvent)
 		}
 	}
 }
 
 //TODO optimize this later but for now walkthru all the different types. Use priority queue/multiple threads if needed.
 func (jb *Journalbeat) logProcessor() {
+	defer jb.wg.Done()
+	// logProcessor is the sole writer to cursorChan, so it owns closing it
+	// once the pipeline has fully drained.
+	defer close(jb.cursorChan)
+
 	logp.Info("Started the thread which consumes log messages and publishes it")
 	tickChan := time.NewTicker(jb.config.FlushLogInterval)
+	defer tickChan.Stop()
 	for {
 		select {
 		case <-tickChan.C:
 			//here we need to walk thru all the map entries and flush out the ones
 			//which have been sitting there for some time.
 			jb.flushStaleLogMessages()
+			if jb.metrics != nil {
+				jb.bufferedEntries.Update(int64(len(jb.journalTypeOutstandingLogBuffer)))
+			}
 
-		case channelEvent := <-jb.incomingLogMessages:
+		case channelEvent, ok := <-jb.incomingLogMessages:
+			if !ok {
+				// incomingLogMessages is closed once the journal follow
+				// loop has stopped; flush everything still buffered and
+				// exit so Stop's WaitGroup can be satisfied.
+				jb.flushAllLogMessages()
+				return
+			}
 			jb.flushOrBufferLogs(channelEvent)
 		}
 	}
@@ -261,64 +555,72 @@ func (jb *Journalbeat) convertMicrosecondsEpochToISO8601(microsecondsEpoch int64
 func (jb *Journalbeat) Run(b *beat.Beat) error {
 	logp.Info("Journalbeat is running!")
 
-	if jb.config.MetricsEnabled {
-		logp.Info("Metrics are enabled" + jb.config.WavefrontCollector)
-		addr, err := net.ResolveTCPAddr("tcp", jb.config.WavefrontCollector)
-		if jb.config.WavefrontCollector != "" && err == nil {
-			logp.Info("Metrics enabled")
-			//make sure the configuration is sane.
-			gauge := metrics.NewGauge()
-			jb.logMessageDelay = gauge
-			counter := metrics.NewCounter()
-			jb.logMessagesPublished = counter
-			registry := metrics.DefaultRegistry
-			//register the metrics with wavefront
-			registry.Register("MessageConsumptionDelay", gauge)
-			registry.Register("MessagesPublished", counter)
-			hostname, err := os.Hostname()
-			if err == nil {
-				jb.config.HostTags["source"] = hostname
-			}
+	if jb.config.Metrics.Backend != metrics.BackendNone {
+		logp.Info("Metrics are enabled, backend: %s", jb.config.Metrics.Backend)
 
-			//validate if we can emit metrics to wavefront.
-			err = wavefront.WavefrontOnce(wavefront.WavefrontConfig{
-				Addr:          addr,
-				Registry:      metrics.DefaultRegistry,
-				FlushInterval: jb.config.MetricsInterval,
-				DurationUnit:  time.Nanosecond,
-				Prefix:        metricPrefix,
-				HostTags:      jb.config.HostTags,
-				Percentiles:   []float64{0.5, 0.75, 0.95, 0.99, 0.999},
-			})
-
-			if err != nil {
-				logp.Err("Metrics collection for log processing on this host is disabled")
-			}
+		if hostname, err := os.Hostname(); err == nil {
+			jb.config.Metrics.HostTags["source"] = hostname
+		}
 
-			go wavefront.Wavefront(registry, jb.config.MetricsInterval, jb.config.HostTags,
-				"", addr)
+		sink, err := metrics.New(jb.config.Metrics, metricPrefix)
+		if err != nil {
+			logp.Err("Setting up %s metrics failed, metrics are disabled: %v", jb.config.Metrics.Backend, err)
 		} else {
-			logp.Err("Cannot parse the IP address of wavefront address" + jb.config.WavefrontCollector)
+			jb.metrics = sink
+			jb.logMessagesPublished = sink.Counter("MessagesPublished")
+			jb.logMessagesPublishedByType = sink.CounterVec("MessagesPublishedByType", "logBufferingType")
+			jb.logMessageDelay = sink.Gauge("MessageConsumptionDelay")
+			jb.bufferedEntries = sink.Gauge("BufferedEntries")
+			jb.cursorWriteLatency = sink.Histogram("CursorWriteLatency")
+			jb.publishErrors = sink.Counter("PublishErrors")
+			jb.journalRestarts = sink.Counter("JournalRestarts")
+			jb.journalLastErrorSeconds = sink.Gauge("JournalLastErrorSeconds")
+
+			if err := sink.Start(jb.done); err != nil {
+				logp.Err("Starting %s metrics failed, metrics are disabled: %v", jb.config.Metrics.Backend, err)
+				jb.metrics = nil
+			}
 		}
 	}
 
 	defer func() {
-		close(jb.cursorChan)
-		jb.client.Close()
+		jb.closeClient()
 		jb.journal.Close()
+		if jb.journalRemote != nil {
+			if err := jb.journalRemote.Close(); err != nil {
+				logp.Err("Flushing journal-remote output failed: %v", err)
+			}
+		}
 	}()
 
 	if jb.config.WriteCursorState {
-		go jb.writeCursorLoop()
+		jb.wg.Add(1)
+		go func() {
+			defer jb.wg.Done()
+			jb.writeCursorLoop()
+		}()
 	}
 
+	jb.wg.Add(1)
 	go jb.logProcessor()
 
 	jb.client = b.Publisher.Connect()
 
 	commonFields := []string{hostNameField, messageField}
 
-	for rawEvent := range journal.Follow(jb.journal, jb.done) {
+	restartCfg := journal.RestartConfig{Backoff: jb.config.Backoff, MaxBackoff: jb.config.MaxBackoff}
+	reopen := func() (journal.Journal, error) { return jb.reopenJournal() }
+	for rawEvent := range journal.FollowWithRestart(jb.journal, reopen, jb.done, restartCfg, jb.onJournalRestart) {
+		if unit, ok := rawEvent.Fields[sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT]; ok && jb.excludedUnit(unit) {
+			continue
+		}
+
+		if jb.journalRemote != nil {
+			if err := jb.journalRemote.PublishEntry(rawEvent); err != nil {
+				logp.Err("Forwarding entry to journal-remote failed: %v", err)
+			}
+		}
+
 		event := common.MapStr{}
 		if _, ok := rawEvent.Fields[containerIdField]; ok {
 			selectedFields := append(commonFields, []string{containerTagField, containerIdField}...)
@@ -360,11 +662,49 @@ func (jb *Journalbeat) Run(b *beat.Beat) error {
 
 		jb.incomingLogMessages <- event
 	}
+
+	// the journal follow loop has ended (Stop was called); stop feeding
+	// logProcessor and let it drain and flush everything buffered.
+	close(jb.incomingLogMessages)
+	jb.wg.Wait()
+
 	return nil
 }
 
-// Stop stops Journalbeat execution
+// closeClient closes jb.client at most once, so both Stop's forced abort
+// and Run's normal cleanup can call it safely.
+func (jb *Journalbeat) closeClient() {
+	jb.closeClientOnce.Do(func() {
+		jb.client.Close()
+	})
+}
+
+// Stop stops Journalbeat execution. It closes the journal follow loop,
+// then waits for the pipeline (logProcessor and writeCursorLoop) to
+// drain and flush everything buffered. If that takes longer than
+// shutdown_timeout -- typically because the publisher output is
+// unreachable and PublishEvent is blocked -- it force-closes jb.client
+// to abort the blocked publish so Run can still return.
 func (jb *Journalbeat) Stop() {
 	logp.Info("Stopping Journalbeat")
 	close(jb.done)
+
+	shutdownTimeout := jb.config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		jb.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		logp.Info("Journalbeat pipeline drained cleanly")
+	case <-time.After(shutdownTimeout):
+		logp.Warn("Timed out after %s waiting for the pipeline to drain; aborting in-flight publishes", shutdownTimeout)
+		jb.closeClient()
+	}
 }
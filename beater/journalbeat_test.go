@@ -0,0 +1,164 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beater
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/medallia/journalbeat/config"
+)
+
+// fakeMatcher records the exact sequence of calls applyMatches makes,
+// so tests can assert on AND/OR structure rather than just "no error".
+type fakeMatcher struct {
+	calls []string
+}
+
+func (f *fakeMatcher) AddMatch(match string) error {
+	f.calls = append(f.calls, "match:"+match)
+	return nil
+}
+
+func (f *fakeMatcher) AddDisjunction() error {
+	f.calls = append(f.calls, "disjunction")
+	return nil
+}
+
+func (f *fakeMatcher) AddConjunction() error {
+	f.calls = append(f.calls, "conjunction")
+	return nil
+}
+
+func TestApplyMatchesPlainTermsAreAllANDed(t *testing.T) {
+	jb := &Journalbeat{config: config.Config{
+		Units: []string{"foo.service"},
+		Filter: config.FilterConfig{
+			Matches:     []string{"MESSAGE=hello"},
+			Identifiers: []string{"sshd"},
+		},
+	}}
+
+	f := &fakeMatcher{}
+	if err := jb.applyMatches(f); err != nil {
+		t.Fatalf("applyMatches: unexpected error: %v", err)
+	}
+
+	want := []string{
+		"match:_SYSTEMD_UNIT=foo.service",
+		"match:SYSLOG_IDENTIFIER=sshd",
+		"match:MESSAGE=hello",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Fatalf("calls = %v, want %v", f.calls, want)
+	}
+}
+
+func TestApplyMatchesDisjunctionsAreORedThenClosed(t *testing.T) {
+	jb := &Journalbeat{config: config.Config{
+		Filter: config.FilterConfig{
+			Disjunctions: [][]string{
+				{"_SYSTEMD_UNIT=a.service"},
+				{"_SYSTEMD_UNIT=b.service"},
+			},
+		},
+	}}
+
+	f := &fakeMatcher{}
+	if err := jb.applyMatches(f); err != nil {
+		t.Fatalf("applyMatches: unexpected error: %v", err)
+	}
+
+	want := []string{
+		"match:_SYSTEMD_UNIT=a.service",
+		"disjunction",
+		"match:_SYSTEMD_UNIT=b.service",
+		"conjunction",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Fatalf("calls = %v, want %v", f.calls, want)
+	}
+}
+
+// TestApplyMatchesPriorityIsANDedNotORedWithDisjunctions is a regression
+// test for the exact bug fixed in 8b19969: priority must be its own
+// AND'd block, not folded into the same OR set as filter.disjunctions.
+func TestApplyMatchesPriorityIsANDedNotORedWithDisjunctions(t *testing.T) {
+	jb := &Journalbeat{config: config.Config{
+		Filter: config.FilterConfig{
+			Disjunctions: [][]string{{"_SYSTEMD_UNIT=foo.service"}},
+			Priority:     "<=err",
+		},
+	}}
+
+	f := &fakeMatcher{}
+	if err := jb.applyMatches(f); err != nil {
+		t.Fatalf("applyMatches: unexpected error: %v", err)
+	}
+
+	want := []string{
+		"match:_SYSTEMD_UNIT=foo.service",
+		"conjunction",
+		"match:PRIORITY=0",
+		"disjunction",
+		"match:PRIORITY=1",
+		"disjunction",
+		"match:PRIORITY=2",
+		"disjunction",
+		"match:PRIORITY=3",
+		"conjunction",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Fatalf("calls = %v, want %v (priority must be AND'd with, not OR'd into, disjunctions)", f.calls, want)
+	}
+}
+
+func TestApplyMatchesConjunctionsAreEachTheirOwnANDedORBlock(t *testing.T) {
+	jb := &Journalbeat{config: config.Config{
+		Filter: config.FilterConfig{
+			Conjunctions: [][]string{
+				{"_SYSTEMD_UNIT=a.service", "_SYSTEMD_UNIT=b.service"},
+				{"PRIORITY=3"},
+			},
+		},
+	}}
+
+	f := &fakeMatcher{}
+	if err := jb.applyMatches(f); err != nil {
+		t.Fatalf("applyMatches: unexpected error: %v", err)
+	}
+
+	want := []string{
+		"match:_SYSTEMD_UNIT=a.service",
+		"disjunction",
+		"match:_SYSTEMD_UNIT=b.service",
+		"conjunction",
+		"match:PRIORITY=3",
+		"conjunction",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Fatalf("calls = %v, want %v", f.calls, want)
+	}
+}
+
+func TestApplyMatchesInvalidPriorityReturnsError(t *testing.T) {
+	jb := &Journalbeat{config: config.Config{
+		Filter: config.FilterConfig{Priority: "bogus"},
+	}}
+
+	if err := jb.applyMatches(&fakeMatcher{}); err == nil {
+		t.Fatalf("applyMatches: expected error for invalid priority, got none")
+	}
+}
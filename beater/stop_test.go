@@ -0,0 +1,134 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beater
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+
+	"github.com/medallia/journalbeat/config"
+)
+
+// blockingClient is a fake publisher.Client whose PublishEvent blocks
+// until Close is called, simulating an unreachable output that has
+// wedged a publish in flight.
+type blockingClient struct {
+	mu        sync.Mutex
+	closed    bool
+	closeOnce sync.Once
+	unblock   chan struct{}
+}
+
+func newBlockingClient() *blockingClient {
+	return &blockingClient{unblock: make(chan struct{})}
+}
+
+func (c *blockingClient) PublishEvent(event common.MapStr, opts ...publisher.ClientOption) bool {
+	<-c.unblock
+	return true
+}
+
+func (c *blockingClient) PublishEvents(events []common.MapStr, opts ...publisher.ClientOption) bool {
+	<-c.unblock
+	return true
+}
+
+func (c *blockingClient) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.closeOnce.Do(func() { close(c.unblock) })
+	return nil
+}
+
+func (c *blockingClient) wasClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// TestStopForceClosesClientAfterShutdownTimeout proves Stop's documented
+// guarantee: if the pipeline doesn't drain within shutdown_timeout --
+// here, because a goroutine is stuck in a PublishEvent call that never
+// returns on its own -- Stop force-closes the client to unstick it and
+// still returns promptly, rather than hanging forever.
+func TestStopForceClosesClientAfterShutdownTimeout(t *testing.T) {
+	client := newBlockingClient()
+	jb := &Journalbeat{
+		done:   make(chan struct{}),
+		config: config.Config{ShutdownTimeout: 20 * time.Millisecond},
+		client: client,
+	}
+
+	jb.wg.Add(1)
+	go func() {
+		defer jb.wg.Done()
+		jb.client.PublishEvent(common.MapStr{"message": "stuck"}, publisher.Guaranteed)
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		jb.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("Stop did not return within 1s of a %s shutdown_timeout", jb.config.ShutdownTimeout)
+	}
+
+	if !client.wasClosed() {
+		t.Fatalf("Stop returned without force-closing the client")
+	}
+
+	// The blocked PublishEvent goroutine must also have been unstuck and
+	// exited, not leaked.
+	drained := make(chan struct{})
+	go func() {
+		jb.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatalf("goroutine blocked in PublishEvent did not exit after Stop force-closed the client")
+	}
+}
+
+// TestStopReturnsImmediatelyWhenPipelineIsAlreadyDrained proves Stop
+// doesn't wait out the full shutdown_timeout when there's nothing to
+// drain.
+func TestStopReturnsImmediatelyWhenPipelineIsAlreadyDrained(t *testing.T) {
+	client := newBlockingClient()
+	jb := &Journalbeat{
+		done:   make(chan struct{}),
+		config: config.Config{ShutdownTimeout: time.Hour},
+		client: client,
+	}
+
+	start := time.Now()
+	jb.Stop()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Stop took %s for an already-drained pipeline, want near-immediate return", elapsed)
+	}
+	if client.wasClosed() {
+		t.Fatalf("Stop force-closed the client even though the pipeline drained cleanly")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/medallia/journalbeat/metrics"
+	"github.com/medallia/journalbeat/output"
+)
+
+// SeekMode describes where in the journal Journalbeat should start reading.
+type SeekMode int
+
+// Supported SeekMode values.
+const (
+	SeekModeDefault SeekMode = iota
+	SeekModeCursor
+	SeekModeHead
+	SeekModeTail
+	SeekModeSince
+)
+
+// String forms of the seek modes, as they appear in the config file.
+const (
+	seekModeCursorStr = "cursor"
+	seekModeHeadStr   = "head"
+	seekModeTailStr   = "tail"
+	seekModeSinceStr  = "since"
+)
+
+// Unpack validates and converts the configured string into a SeekMode.
+// It is called by go-ucfg while loading the config, so an invalid
+// seek_position now fails config loading instead of being silently
+// ignored by initJournal's switch statement.
+func (m *SeekMode) Unpack(s string) error {
+	switch s {
+	case "", seekModeCursorStr:
+		*m = SeekModeCursor
+	case seekModeHeadStr:
+		*m = SeekModeHead
+	case seekModeTailStr:
+		*m = SeekModeTail
+	case seekModeSinceStr:
+		*m = SeekModeSince
+	default:
+		return fmt.Errorf("invalid seek_position %q: must be one of cursor, head, tail, since", s)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer so SeekMode prints usefully in logs.
+func (m SeekMode) String() string {
+	switch m {
+	case SeekModeCursor:
+		return seekModeCursorStr
+	case SeekModeHead:
+		return seekModeHeadStr
+	case SeekModeTail:
+		return seekModeTailStr
+	case SeekModeSince:
+		return seekModeSinceStr
+	default:
+		return "default"
+	}
+}
+
+// Config is the Journalbeat configuration as loaded from journalbeat.yml.
+type Config struct {
+	Units  []string     `config:"units"`
+	Filter FilterConfig `config:"filter"`
+
+	SeekPosition       SeekMode      `config:"seek_position"`
+	SeekSince          string        `config:"seek_since"`
+	CursorStateFile    string        `config:"cursor_state_file"`
+	CursorSeekFallback SeekMode      `config:"cursor_seek_fallback"`
+	CursorFlushPeriod  time.Duration `config:"cursor_flush_period"`
+	WriteCursorState   bool          `config:"write_cursor_state"`
+
+	ConvertToNumbers     bool   `config:"convert_to_numbers"`
+	CleanFieldNames      bool   `config:"clean_field_names"`
+	MoveMetadataLocation string `config:"move_metadata_location"`
+	DefaultType          string `config:"default_type"`
+
+	FlushLogInterval time.Duration `config:"flush_log_interval"`
+
+	// ShutdownTimeout bounds how long Stop waits for the pipeline to
+	// drain before forcibly aborting any in-flight publish.
+	ShutdownTimeout time.Duration `config:"shutdown_timeout"`
+
+	// Backoff and MaxBackoff control how quickly the journal follow
+	// loop retries after a restart, growing exponentially up to
+	// MaxBackoff.
+	Backoff    time.Duration `config:"backoff"`
+	MaxBackoff time.Duration `config:"max_backoff"`
+
+	Metrics metrics.Config `config:"metrics"`
+
+	Output OutputConfig `config:"output"`
+}
+
+// OutputConfig groups the non-Elastic sinks Journalbeat can forward
+// journal entries to.
+type OutputConfig struct {
+	JournalRemote output.JournalRemoteConfig `config:"journal_remote"`
+}
+
+// DefaultConfig holds the configuration defaults used when a field is not
+// present in journalbeat.yml.
+var DefaultConfig = Config{
+	SeekPosition:       SeekModeCursor,
+	CursorSeekFallback: SeekModeDefault,
+	CursorStateFile:    "/var/lib/journalbeat/journalbeat-cursor-state",
+	CursorFlushPeriod:  5 * time.Second,
+	WriteCursorState:   true,
+
+	MoveMetadataLocation: "json",
+	DefaultType:          "journal",
+
+	FlushLogInterval: 30 * time.Second,
+	ShutdownTimeout:  30 * time.Second,
+	Backoff:          1 * time.Second,
+	MaxBackoff:       20 * time.Second,
+
+	Output: OutputConfig{
+		JournalRemote: output.DefaultJournalRemoteConfig,
+	},
+
+	Metrics: metrics.DefaultConfig,
+}
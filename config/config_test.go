@@ -0,0 +1,69 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "testing"
+
+func TestSeekModeUnpack(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    SeekMode
+		wantErr bool
+	}{
+		{in: "", want: SeekModeCursor},
+		{in: "cursor", want: SeekModeCursor},
+		{in: "head", want: SeekModeHead},
+		{in: "tail", want: SeekModeTail},
+		{in: "since", want: SeekModeSince},
+		{in: "bogus", wantErr: true},
+	}
+
+	for _, c := range cases {
+		var m SeekMode
+		err := m.Unpack(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("Unpack(%q): expected error, got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unpack(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if m != c.want {
+			t.Errorf("Unpack(%q) = %v, want %v", c.in, m, c.want)
+		}
+	}
+}
+
+func TestSeekModeString(t *testing.T) {
+	cases := []struct {
+		mode SeekMode
+		want string
+	}{
+		{mode: SeekModeCursor, want: "cursor"},
+		{mode: SeekModeHead, want: "head"},
+		{mode: SeekModeTail, want: "tail"},
+		{mode: SeekModeSince, want: "since"},
+		{mode: SeekModeDefault, want: "default"},
+	}
+
+	for _, c := range cases {
+		if got := c.mode.String(); got != c.want {
+			t.Errorf("SeekMode(%d).String() = %q, want %q", c.mode, got, c.want)
+		}
+	}
+}
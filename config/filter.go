@@ -0,0 +1,114 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// priorityLevels maps syslog priority names to their numeric PRIORITY
+// field values, most to least severe.
+var priorityLevels = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// FilterConfig describes which journal entries Journalbeat ingests. It
+// mirrors sd-journal's own matching primitives (matches, disjunctions,
+// conjunctions) plus a handful of convenience shortcuts on top.
+type FilterConfig struct {
+	// Matches is a list of "FIELD=value" terms, AND-combined with each
+	// other and with every other filter below.
+	Matches []string `config:"matches"`
+
+	// Disjunctions is a list of AND-groups; the groups themselves are
+	// OR-combined via sd_journal_add_disjunction. A group must be
+	// non-empty -- an empty group would match every entry, which is
+	// almost certainly not what was intended.
+	Disjunctions [][]string `config:"disjunctions"`
+
+	// Conjunctions is a list of OR-blocks; unlike Disjunctions, the
+	// blocks themselves are AND-combined with each other (and with
+	// every other filter in this struct). Each block must be
+	// non-empty -- an empty block would match nothing, which would
+	// make the whole filter match nothing.
+	Conjunctions [][]string `config:"conjunctions"`
+
+	IncludeUnits []string `config:"include_units"`
+	ExcludeUnits []string `config:"exclude_units"`
+	Identifiers  []string `config:"identifiers"`
+
+	// Priority is a syslog priority shortcut, e.g. "<=warning" to match
+	// that level and everything more severe, or "warning" for an exact
+	// match.
+	Priority string `config:"priority"`
+}
+
+// Validate rejects filter configurations that would silently match
+// nothing useful or everything: empty disjunction groups, empty
+// conjunction blocks, and unrecognized priority names. It is called by go-ucfg after Unpack.
+func (f FilterConfig) Validate() error {
+	for i, group := range f.Disjunctions {
+		if len(group) == 0 {
+			return fmt.Errorf("filter.disjunctions[%d] is empty: it would match every journal entry", i)
+		}
+	}
+
+	for i, block := range f.Conjunctions {
+		if len(block) == 0 {
+			return fmt.Errorf("filter.conjunctions[%d] is empty: it would match nothing", i)
+		}
+	}
+
+	if _, err := f.priorityGroup(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// priorityGroup expands Priority into a disjunction group of
+// "PRIORITY=N" terms. It returns a nil group (and nil error) when no
+// priority filter is configured.
+func (f FilterConfig) priorityGroup() ([]string, error) {
+	if f.Priority == "" {
+		return nil, nil
+	}
+
+	upToLevel := strings.HasPrefix(f.Priority, "<=")
+	spec := strings.TrimPrefix(f.Priority, "<=")
+
+	level, ok := priorityLevels[spec]
+	if !ok {
+		return nil, fmt.Errorf("invalid priority %q: must be one of emerg, alert, crit, err, warning, notice, info, debug, optionally prefixed with \"<=\"", f.Priority)
+	}
+
+	if !upToLevel {
+		return []string{fmt.Sprintf("PRIORITY=%d", level)}, nil
+	}
+
+	group := make([]string, 0, level+1)
+	for p := 0; p <= level; p++ {
+		group = append(group, fmt.Sprintf("PRIORITY=%d", p))
+	}
+	return group, nil
+}
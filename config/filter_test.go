@@ -0,0 +1,98 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterConfigPriorityGroup(t *testing.T) {
+	cases := []struct {
+		name     string
+		priority string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "unset", priority: "", want: nil},
+		{name: "exact", priority: "warning", want: []string{"PRIORITY=4"}},
+		{
+			name:     "up to level",
+			priority: "<=err",
+			want:     []string{"PRIORITY=0", "PRIORITY=1", "PRIORITY=2", "PRIORITY=3"},
+		},
+		{name: "invalid", priority: "bogus", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := FilterConfig{Priority: c.priority}
+			got, err := f.priorityGroup()
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("priorityGroup(%q): expected error, got none", c.priority)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("priorityGroup(%q): unexpected error: %v", c.priority, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("priorityGroup(%q) = %v, want %v", c.priority, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFilterConfigValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		filter  FilterConfig
+		wantErr bool
+	}{
+		{name: "empty config", filter: FilterConfig{}},
+		{
+			name:   "non-empty disjunction and conjunction",
+			filter: FilterConfig{Disjunctions: [][]string{{"_SYSTEMD_UNIT=foo.service"}}, Conjunctions: [][]string{{"PRIORITY=4"}}},
+		},
+		{
+			name:    "empty disjunction group",
+			filter:  FilterConfig{Disjunctions: [][]string{{}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty conjunction block",
+			filter:  FilterConfig{Conjunctions: [][]string{{}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid priority",
+			filter:  FilterConfig{Priority: "bogus"},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.filter.Validate()
+			if c.wantErr && err == nil {
+				t.Fatalf("Validate(): expected error, got none")
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("Validate(): unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journal wraps sdjournal with a simple channel-based follow loop.
+package journal
+
+import (
+	"time"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// waitDuration bounds how long a single Wait call blocks for new entries,
+// so the follow loop can notice a closed done channel promptly.
+const waitDuration = 1 * time.Second
+
+// Journal is the subset of *sdjournal.Journal that Follow and
+// FollowWithRestart depend on. Narrowing to this interface lets the
+// restart/backoff/cancellation logic below be exercised with a fake in
+// tests, without a live journald. *sdjournal.Journal satisfies it.
+type Journal interface {
+	Next() (uint64, error)
+	GetEntry() (*sdjournal.JournalEntry, error)
+	Wait(timeout time.Duration) int
+	Close() error
+}
+
+// Follow streams entries from j until done is closed, or until a
+// sd_journal call returns an error other than an empty read. The
+// returned error channel receives that error and is then closed; it is
+// itself closed with no value if Follow stopped because done was
+// closed. Callers that want automatic recovery should use
+// FollowWithRestart instead of calling Follow directly.
+func Follow(j Journal, done chan struct{}) (<-chan *sdjournal.JournalEntry, <-chan error) {
+	out := make(chan *sdjournal.JournalEntry)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			c, err := j.Next()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if c == 0 {
+				// caught up with the journal tail; wait for new entries
+				j.Wait(waitDuration)
+				continue
+			}
+
+			entry, err := j.GetEntry()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case out <- entry:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// RestartConfig configures the backoff FollowWithRestart uses between
+// restart attempts.
+type RestartConfig struct {
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// FollowWithRestart wraps Follow with automatic recovery. When the
+// underlying journal ends with a non-cancellation error (rotation, a
+// corrupted file, a cgo panic recovered by sdjournal), it closes the
+// current journal, calls reopen to get a fresh one seeked to a sane
+// position, and resumes following. It waits the current backoff before
+// each reopen attempt; the backoff starts at cfg.Backoff, doubles after
+// each successful restart up to cfg.MaxBackoff, and is not reset.
+// Failed reopen attempts are retried at the same backoff. onRestart, if
+// non-nil, is invoked after each successful reopen so callers can emit metrics or a
+// sentinel event; it is not called when Follow ends because done was
+// closed.
+func FollowWithRestart(initial Journal, reopen func() (Journal, error), done chan struct{}, cfg RestartConfig, onRestart func(cause error)) <-chan *sdjournal.JournalEntry {
+	out := make(chan *sdjournal.JournalEntry)
+
+	backoff := cfg.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 20 * time.Second
+	}
+
+	go func() {
+		defer close(out)
+
+		currentBackoff := backoff
+		j := initial
+
+		for {
+			entries, errs := Follow(j, done)
+			for entry := range entries {
+				select {
+				case out <- entry:
+				case <-done:
+					return
+				}
+			}
+
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			cause, ok := <-errs
+			if !ok {
+				// Follow produced no error: it stopped because done
+				// was closed while idle between entries.
+				return
+			}
+
+			j.Close()
+			logp.Warn("Journal follow loop ended with error, restarting: %v", cause)
+
+			if !sleepOrDone(currentBackoff, done) {
+				return
+			}
+
+			var err error
+			for {
+				if j, err = reopen(); err == nil {
+					break
+				}
+				logp.Err("Reopening journal failed, retrying in %s: %v", currentBackoff, err)
+				if !sleepOrDone(currentBackoff, done) {
+					return
+				}
+			}
+
+			currentBackoff *= 2
+			if currentBackoff > maxBackoff {
+				currentBackoff = maxBackoff
+			}
+
+			if onRestart != nil {
+				onRestart(cause)
+			}
+		}
+	}()
+
+	return out
+}
+
+// sleepOrDone waits for d, returning false early if done is closed first.
+func sleepOrDone(d time.Duration, done chan struct{}) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/sdjournal"
+)
+
+// fakeJournal is a scriptable Journal: each Next call reports the next
+// queued entry, or -- once entries are exhausted -- pops and returns the
+// next queued error. Wait is a no-op since the fake never needs to
+// actually block waiting for new entries to show up.
+type fakeJournal struct {
+	mu      sync.Mutex
+	entries []*sdjournal.JournalEntry
+	errs    []error
+	closed  int
+}
+
+func (f *fakeJournal) Next() (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.entries) > 0 {
+		return 1, nil
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (f *fakeJournal) GetEntry() (*sdjournal.JournalEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry := f.entries[0]
+	f.entries = f.entries[1:]
+	return entry, nil
+}
+
+func (f *fakeJournal) Wait(time.Duration) int { return 0 }
+
+func (f *fakeJournal) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed++
+	return nil
+}
+
+func entry(cursor string) *sdjournal.JournalEntry {
+	return &sdjournal.JournalEntry{Cursor: cursor}
+}
+
+func TestFollowStopsOnDone(t *testing.T) {
+	j := &fakeJournal{}
+	done := make(chan struct{})
+
+	out, errs := Follow(j, done)
+	close(done)
+
+	if _, ok := <-out; ok {
+		t.Fatalf("expected out to be closed with no entries")
+	}
+	if _, ok := <-errs; ok {
+		t.Fatalf("expected errs to be closed with no error")
+	}
+}
+
+func TestFollowReportsError(t *testing.T) {
+	wantErr := errors.New("boom")
+	j := &fakeJournal{entries: []*sdjournal.JournalEntry{entry("a")}, errs: []error{wantErr}}
+	done := make(chan struct{})
+	defer close(done)
+
+	out, errs := Follow(j, done)
+
+	got := <-out
+	if got.Cursor != "a" {
+		t.Fatalf("got entry cursor %q, want %q", got.Cursor, "a")
+	}
+
+	if _, ok := <-out; ok {
+		t.Fatalf("expected out to be closed after the error")
+	}
+	if err := <-errs; err != wantErr {
+		t.Fatalf("got error %v, want %v", err, wantErr)
+	}
+}
+
+func drain(t *testing.T, out <-chan *sdjournal.JournalEntry, n int, timeout time.Duration) []*sdjournal.JournalEntry {
+	t.Helper()
+	var got []*sdjournal.JournalEntry
+	for len(got) < n {
+		select {
+		case entry, ok := <-out:
+			if !ok {
+				t.Fatalf("out closed early after %d of %d entries", len(got), n)
+			}
+			got = append(got, entry)
+		case <-time.After(timeout):
+			t.Fatalf("timed out waiting for entry %d of %d", len(got)+1, n)
+		}
+	}
+	return got
+}
+
+func TestFollowWithRestartReopensAfterError(t *testing.T) {
+	initial := &fakeJournal{
+		entries: []*sdjournal.JournalEntry{entry("before-restart")},
+		errs:    []error{errors.New("journal rotated")},
+	}
+	restarted := &fakeJournal{entries: []*sdjournal.JournalEntry{entry("after-restart")}}
+
+	var reopenCalls int
+	var mu sync.Mutex
+	reopen := func() (Journal, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reopenCalls++
+		return restarted, nil
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var restartCauses []error
+	out := FollowWithRestart(initial, reopen, done, RestartConfig{Backoff: time.Millisecond, MaxBackoff: time.Millisecond}, func(cause error) {
+		mu.Lock()
+		defer mu.Unlock()
+		restartCauses = append(restartCauses, cause)
+	})
+
+	got := drain(t, out, 2, time.Second)
+	if got[0].Cursor != "before-restart" || got[1].Cursor != "after-restart" {
+		t.Fatalf("got entries %q, %q; want %q, %q", got[0].Cursor, got[1].Cursor, "before-restart", "after-restart")
+	}
+
+	if initial.closed != 1 {
+		t.Fatalf("initial journal closed %d times, want 1", initial.closed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if reopenCalls != 1 {
+		t.Fatalf("reopen called %d times, want 1", reopenCalls)
+	}
+	if len(restartCauses) != 1 || restartCauses[0].Error() != "journal rotated" {
+		t.Fatalf("onRestart called with %v, want one call with %q", restartCauses, "journal rotated")
+	}
+}
+
+func TestFollowWithRestartRetriesReopenWithBackoff(t *testing.T) {
+	initial := &fakeJournal{errs: []error{errors.New("boom")}}
+	restarted := &fakeJournal{entries: []*sdjournal.JournalEntry{entry("after-restart")}}
+
+	var mu sync.Mutex
+	var reopenCalls int
+	reopen := func() (Journal, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reopenCalls++
+		if reopenCalls < 3 {
+			return nil, errors.New("reopen failed")
+		}
+		return restarted, nil
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	out := FollowWithRestart(initial, reopen, done, RestartConfig{Backoff: time.Millisecond, MaxBackoff: time.Millisecond}, nil)
+
+	got := drain(t, out, 1, time.Second)
+	if got[0].Cursor != "after-restart" {
+		t.Fatalf("got entry %q, want %q", got[0].Cursor, "after-restart")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if reopenCalls != 3 {
+		t.Fatalf("reopen called %d times, want 3", reopenCalls)
+	}
+}
+
+func TestFollowWithRestartStopsOnDoneWhileBackingOff(t *testing.T) {
+	initial := &fakeJournal{errs: []error{errors.New("boom")}}
+
+	reopen := func() (Journal, error) {
+		t.Fatalf("reopen should not be called once done is closed during backoff")
+		return nil, nil
+	}
+
+	done := make(chan struct{})
+	out := FollowWithRestart(initial, reopen, done, RestartConfig{Backoff: time.Hour, MaxBackoff: time.Hour}, nil)
+
+	close(done)
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatalf("expected out to be closed with no entries")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("FollowWithRestart did not stop promptly after done was closed")
+	}
+}
+
+func TestSleepOrDoneReturnsTrueAfterDuration(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	start := time.Now()
+	if !sleepOrDone(10*time.Millisecond, done) {
+		t.Fatalf("sleepOrDone returned false, want true")
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("sleepOrDone returned after %s, want at least 10ms", elapsed)
+	}
+}
+
+func TestSleepOrDoneReturnsFalseWhenDone(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+
+	if sleepOrDone(time.Hour, done) {
+		t.Fatalf("sleepOrDone returned true, want false once done is closed")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusSink exposes a registry of metrics for scraping at Addr/Path,
+// the standard scrape endpoint for operators who don't run Wavefront.
+type prometheusSink struct {
+	registry *prometheus.Registry
+	addr     string
+	path     string
+	prefix   string
+}
+
+func newPrometheusSink(cfg Config, prefix string) (Sink, error) {
+	return &prometheusSink{
+		registry: prometheus.NewRegistry(),
+		addr:     cfg.Prometheus.Addr,
+		path:     cfg.Prometheus.Path,
+		prefix:   sanitizeName(prefix),
+	}, nil
+}
+
+func (s *prometheusSink) metricName(name string) string {
+	return s.prefix + "_" + sanitizeName(name)
+}
+
+func (s *prometheusSink) Counter(name string) Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: s.metricName(name)})
+	s.registry.MustRegister(c)
+	return prometheusCounter{c}
+}
+
+func (s *prometheusSink) Gauge(name string) Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: s.metricName(name)})
+	s.registry.MustRegister(g)
+	return prometheusGauge{g}
+}
+
+func (s *prometheusSink) Histogram(name string) Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: s.metricName(name)})
+	s.registry.MustRegister(h)
+	return prometheusHistogram{h}
+}
+
+func (s *prometheusSink) CounterVec(name, label string) CounterVec {
+	v := prometheus.NewCounterVec(prometheus.CounterOpts{Name: s.metricName(name)}, []string{label})
+	s.registry.MustRegister(v)
+	return prometheusCounterVec{v}
+}
+
+// Start serves the Prometheus scrape endpoint until done is closed.
+func (s *prometheusSink) Start(done chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.path, promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: s.addr, Handler: mux}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logp.Err("Prometheus metrics server failed: %v", err)
+		}
+	}()
+
+	go func() {
+		<-done
+		server.Shutdown(context.Background())
+	}()
+
+	return nil
+}
+
+type prometheusCounter struct{ c prometheus.Counter }
+
+func (p prometheusCounter) Inc(delta int64) { p.c.Add(float64(delta)) }
+
+type prometheusGauge struct{ g prometheus.Gauge }
+
+func (p prometheusGauge) Update(value int64) { p.g.Set(float64(value)) }
+
+type prometheusHistogram struct{ h prometheus.Histogram }
+
+func (p prometheusHistogram) Update(value int64) { p.h.Observe(float64(value)) }
+
+type prometheusCounterVec struct{ v *prometheus.CounterVec }
+
+func (p prometheusCounterVec) WithLabel(value string) Counter {
+	return prometheusCounter{p.v.WithLabelValues(value)}
+}
+
+// sanitizeName coerces name into the character set Prometheus metric
+// names allow: [a-zA-Z_:][a-zA-Z0-9_:]*.
+func sanitizeName(name string) string {
+	out := []rune(name)
+	for i, r := range out {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "already valid", in: "logging_journalbeat", want: "logging_journalbeat"},
+		{name: "dots become underscores", in: "logging.journalbeat", want: "logging_journalbeat"},
+		{name: "leading digit becomes underscore", in: "9lives", want: "_lives"},
+		{name: "digit allowed after first char", in: "a9", want: "a9"},
+		{name: "colon is preserved", in: "logging:journalbeat", want: "logging:journalbeat"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := sanitizeName(c.in); got != c.want {
+				t.Errorf("sanitizeName(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
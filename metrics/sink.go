@@ -0,0 +1,133 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics abstracts Journalbeat's instrumentation behind a Sink
+// interface, so the beater can be wired against Wavefront, Prometheus,
+// or nothing at all without caring which one is active.
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backend selects which Sink implementation New constructs.
+type Backend string
+
+// Supported backends.
+const (
+	BackendNone       Backend = "none"
+	BackendWavefront  Backend = "wavefront"
+	BackendPrometheus Backend = "prometheus"
+)
+
+// Unpack validates the configured backend name.
+func (b *Backend) Unpack(s string) error {
+	switch Backend(s) {
+	case "":
+		*b = BackendNone
+	case BackendNone, BackendWavefront, BackendPrometheus:
+		*b = Backend(s)
+	default:
+		return fmt.Errorf("invalid metrics.backend %q: must be one of wavefront, prometheus, none", s)
+	}
+	return nil
+}
+
+// Config configures the metrics Sink.
+type Config struct {
+	Backend  Backend           `config:"backend"`
+	Interval time.Duration     `config:"interval"`
+	HostTags map[string]string `config:"host_tags"`
+
+	Wavefront  WavefrontConfig  `config:"wavefront"`
+	Prometheus PrometheusConfig `config:"prometheus"`
+}
+
+// WavefrontConfig configures the Wavefront push sink.
+type WavefrontConfig struct {
+	Collector string `config:"collector"`
+}
+
+// PrometheusConfig configures the Prometheus pull sink.
+type PrometheusConfig struct {
+	Addr string `config:"addr"`
+	Path string `config:"path"`
+}
+
+// DefaultConfig holds the metrics defaults used when a field is not
+// present in journalbeat.yml.
+var DefaultConfig = Config{
+	Backend:  BackendNone,
+	Interval: 10 * time.Second,
+	HostTags: map[string]string{},
+	Prometheus: PrometheusConfig{
+		Addr: ":9273",
+		Path: "/metrics",
+	},
+}
+
+// Counter is a monotonically increasing value, e.g. messages published.
+type Counter interface {
+	Inc(delta int64)
+}
+
+// Gauge is a value that can go up or down, e.g. buffered entry count.
+type Gauge interface {
+	Update(value int64)
+}
+
+// Histogram records a distribution of observed values, e.g. publish
+// latency.
+type Histogram interface {
+	Update(value int64)
+}
+
+// CounterVec is a family of Counters distinguished by a single label,
+// e.g. messages published per logBufferingType.
+type CounterVec interface {
+	WithLabel(value string) Counter
+}
+
+// Sink is the abstraction Journalbeat's metrics wiring programs
+// against.
+type Sink interface {
+	Counter(name string) Counter
+	Gauge(name string) Gauge
+	Histogram(name string) Histogram
+	CounterVec(name string, label string) CounterVec
+
+	// Start begins exporting metrics until done is closed: pushing to
+	// Wavefront on Interval, or serving the Prometheus scrape endpoint.
+	Start(done chan struct{}) error
+}
+
+// New constructs the Sink selected by cfg.Backend. prefix namespaces
+// every metric name (e.g. "logging.journalbeat"). Callers only invoke
+// New when cfg.Backend isn't BackendNone -- the "no metrics" state is
+// represented by a nil Sink, which every call site already nil-checks
+// before recording a metric, rather than by a separate no-op
+// implementation of this interface.
+func New(cfg Config, prefix string) (Sink, error) {
+	switch cfg.Backend {
+	case BackendWavefront:
+		return newWavefrontSink(cfg, prefix)
+	case BackendPrometheus:
+		return newPrometheusSink(cfg, prefix)
+	case BackendNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown metrics backend %q", cfg.Backend)
+	}
+}
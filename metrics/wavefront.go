@@ -0,0 +1,121 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	wavefront "github.com/wavefronthq/go-metrics-wavefront"
+)
+
+// wavefrontSink pushes a go-metrics registry to a Wavefront proxy/collector
+// on Interval, the way Run used to do inline.
+type wavefrontSink struct {
+	registry gometrics.Registry
+	addr     *net.TCPAddr
+	interval time.Duration
+	hostTags map[string]string
+	prefix   string
+
+	mu          sync.Mutex
+	counterVecs map[string]map[string]Counter
+}
+
+func newWavefrontSink(cfg Config, prefix string) (Sink, error) {
+	addr, err := net.ResolveTCPAddr("tcp", cfg.Wavefront.Collector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing wavefront collector address %q failed: %v", cfg.Wavefront.Collector, err)
+	}
+
+	return &wavefrontSink{
+		registry:    gometrics.DefaultRegistry,
+		addr:        addr,
+		interval:    cfg.Interval,
+		hostTags:    cfg.HostTags,
+		prefix:      prefix,
+		counterVecs: make(map[string]map[string]Counter),
+	}, nil
+}
+
+func (s *wavefrontSink) Counter(name string) Counter {
+	c := gometrics.NewCounter()
+	s.registry.Register(name, c)
+	return c
+}
+
+func (s *wavefrontSink) Gauge(name string) Gauge {
+	g := gometrics.NewGauge()
+	s.registry.Register(name, g)
+	return g
+}
+
+func (s *wavefrontSink) Histogram(name string) Histogram {
+	h := gometrics.NewHistogram(gometrics.NewUniformSample(1028))
+	s.registry.Register(name, h)
+	return h
+}
+
+// CounterVec fakes a labeled counter family for Wavefront by registering
+// one plain counter per label value, named "<name>.<value>".
+func (s *wavefrontSink) CounterVec(name, label string) CounterVec {
+	return &wavefrontCounterVec{sink: s, name: name}
+}
+
+func (s *wavefrontSink) Start(done chan struct{}) error {
+	err := wavefront.WavefrontOnce(wavefront.WavefrontConfig{
+		Addr:          s.addr,
+		Registry:      s.registry,
+		FlushInterval: s.interval,
+		DurationUnit:  time.Nanosecond,
+		Prefix:        s.prefix,
+		HostTags:      s.hostTags,
+		Percentiles:   []float64{0.5, 0.75, 0.95, 0.99, 0.999},
+	})
+	if err != nil {
+		return fmt.Errorf("validating wavefront config failed: %v", err)
+	}
+
+	go wavefront.Wavefront(s.registry, s.interval, s.hostTags, s.prefix, s.addr)
+
+	return nil
+}
+
+type wavefrontCounterVec struct {
+	sink *wavefrontSink
+	name string
+}
+
+func (v *wavefrontCounterVec) WithLabel(value string) Counter {
+	v.sink.mu.Lock()
+	defer v.sink.mu.Unlock()
+
+	byValue, ok := v.sink.counterVecs[v.name]
+	if !ok {
+		byValue = make(map[string]Counter)
+		v.sink.counterVecs[v.name] = byValue
+	}
+
+	if c, ok := byValue[value]; ok {
+		return c
+	}
+
+	c := v.sink.Counter(fmt.Sprintf("%s.%s", v.name, value))
+	byValue[value] = c
+	return c
+}
@@ -0,0 +1,256 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output contains alternative sinks Journalbeat can forward
+// journal entries to, alongside (or instead of) the Elastic publisher.
+package output
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// journalExportContentType is the Content-Type systemd-journal-remote
+// expects for the Journal Export Format.
+const journalExportContentType = "application/vnd.fdo.journal"
+
+// verbatimFields are copied through to the export stream unchanged; they
+// carry journald-internal metadata that downstream tooling relies on.
+var verbatimFields = map[string]bool{
+	"__REALTIME_TIMESTAMP":  true,
+	"__MONOTONIC_TIMESTAMP": true,
+	"__CURSOR":              true,
+}
+
+// JournalRemoteConfig configures the output.journal_remote sink.
+type JournalRemoteConfig struct {
+	URL       string        `config:"url"`
+	BatchSize int           `config:"batch_size"`
+	Timeout   time.Duration `config:"timeout"`
+
+	// MaxPending bounds how many entries Flush will hold onto across
+	// repeated upload failures. Once it's reached, PublishEntry drops
+	// the oldest buffered entries to make room for new ones rather than
+	// growing pending without limit for as long as the remote endpoint
+	// is unreachable.
+	MaxPending int `config:"max_pending"`
+
+	// CAFile, CertFile and KeyFile configure the client's TLS transport
+	// for https:// endpoints -- the common case for an internally-run
+	// systemd-journal-remote behind a private CA or requiring client
+	// certificates. InsecureSkipVerify disables server certificate
+	// verification entirely and should only be used for testing.
+	CAFile             string `config:"ca_file"`
+	CertFile           string `config:"cert_file"`
+	KeyFile            string `config:"key_file"`
+	InsecureSkipVerify bool   `config:"insecure_skip_verify"`
+}
+
+// DefaultJournalRemoteConfig holds the sink's defaults.
+var DefaultJournalRemoteConfig = JournalRemoteConfig{
+	BatchSize:  100,
+	Timeout:    10 * time.Second,
+	MaxPending: 10000,
+}
+
+// JournalRemote streams journal entries to a systemd-journal-remote
+// endpoint, serialized in the systemd Journal Export Format.
+type JournalRemote struct {
+	config JournalRemoteConfig
+	client *http.Client
+
+	pending []*sdjournal.JournalEntry
+}
+
+// NewJournalRemote creates a JournalRemote sink for the given config.
+func NewJournalRemote(cfg JournalRemoteConfig) (*JournalRemote, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("output.journal_remote.url must be set")
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournalRemote{
+		config: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// buildTLSConfig assembles the *tls.Config for cfg's TLS options. It
+// returns nil when none of them are set, leaving the transport to use
+// Go's default TLS config.
+func buildTLSConfig(cfg JournalRemoteConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		ca, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading output.journal_remote.ca_file failed: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("output.journal_remote.ca_file %q contains no usable certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading output.journal_remote client certificate failed: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// PublishEntry buffers an entry and flushes the batch to
+// systemd-journal-remote once it reaches batch_size. During a sustained
+// outage, Flush keeps failing and pending keeps growing; once it
+// reaches max_pending the oldest entries are dropped to make room so
+// memory use stays bounded instead of growing for as long as the outage
+// lasts.
+func (jr *JournalRemote) PublishEntry(entry *sdjournal.JournalEntry) error {
+	jr.pending = append(jr.pending, entry)
+
+	if max := jr.config.MaxPending; max > 0 && len(jr.pending) > max {
+		dropped := len(jr.pending) - max
+		logp.Warn("journal-remote output has %d entries pending (max_pending=%d); dropping the %d oldest", len(jr.pending), max, dropped)
+		jr.pending = jr.pending[dropped:]
+	}
+
+	if len(jr.pending) < jr.config.BatchSize {
+		return nil
+	}
+	return jr.Flush()
+}
+
+// Flush serializes and PUTs any buffered entries to the remote endpoint,
+// regardless of whether batch_size has been reached. The batch is only
+// dropped once systemd-journal-remote has actually accepted it -- on any
+// earlier error jr.pending is left untouched, so the next PublishEntry
+// or Flush call retries the same batch instead of silently losing it.
+func (jr *JournalRemote) Flush() error {
+	if len(jr.pending) == 0 {
+		return nil
+	}
+
+	var buf strings.Builder
+	for _, entry := range jr.pending {
+		writeExportEntry(&buf, entry)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, jr.config.URL+"/upload", strings.NewReader(buf.String()))
+	if err != nil {
+		return fmt.Errorf("building journal-remote request failed: %v", err)
+	}
+	req.Header.Set("Content-Type", journalExportContentType)
+
+	resp, err := jr.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading to journal-remote failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("journal-remote upload rejected with status %s", resp.Status)
+	}
+
+	jr.pending = jr.pending[:0]
+	return nil
+}
+
+// Close flushes any pending entries.
+func (jr *JournalRemote) Close() error {
+	return jr.Flush()
+}
+
+// writeExportEntry serializes a single journal entry in the systemd
+// Journal Export Format (see systemd.journal-fields(7) and
+// journal-remote's "Journal Export Format" documentation) and appends
+// the blank-line entry separator.
+func writeExportEntry(w *strings.Builder, entry *sdjournal.JournalEntry) {
+	writeExportField(w, "__CURSOR", entry.Cursor)
+	writeExportField(w, "__REALTIME_TIMESTAMP", fmt.Sprintf("%d", entry.RealtimeTimestamp))
+	writeExportField(w, "__MONOTONIC_TIMESTAMP", fmt.Sprintf("%d", entry.MonotonicTimestamp))
+
+	for field, value := range entry.Fields {
+		if verbatimFields[field] {
+			continue
+		}
+		writeExportField(w, field, value)
+	}
+
+	w.WriteString("\n")
+}
+
+// writeExportField emits a single FIELD=value line when value is valid
+// UTF-8 with no control characters other than tab, or falls back to the
+// binary-safe FIELD\n<uint64 length><value>\n form otherwise.
+func writeExportField(w *strings.Builder, field, value string) {
+	if isExportSafe(value) {
+		fmt.Fprintf(w, "%s=%s\n", field, value)
+		return
+	}
+
+	w.WriteString(field)
+	w.WriteString("\n")
+
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(value)))
+	w.Write(length[:])
+	w.WriteString(value)
+	w.WriteString("\n")
+}
+
+// isExportSafe reports whether value can be written as a plain
+// FIELD=value line: valid UTF-8 with no control characters other than
+// tab.
+func isExportSafe(value string) bool {
+	if !utf8.ValidString(value) {
+		return false
+	}
+	for _, r := range value {
+		if r == '\t' {
+			continue
+		}
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
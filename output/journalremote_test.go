@@ -0,0 +1,166 @@
+// Copyright 2017 Marcus Heese
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coreos/go-systemd/sdjournal"
+)
+
+func TestIsExportSafe(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "plain text", value: "hello world", want: true},
+		{name: "tab is allowed", value: "hello\tworld", want: true},
+		{name: "newline is unsafe", value: "hello\nworld", want: false},
+		{name: "invalid utf8 is unsafe", value: "hello\xffworld", want: false},
+		{name: "other control char is unsafe", value: "hello\x01world", want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isExportSafe(c.value); got != c.want {
+				t.Errorf("isExportSafe(%q) = %v, want %v", c.value, got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteExportFieldSafeValue(t *testing.T) {
+	var buf strings.Builder
+	writeExportField(&buf, "MESSAGE", "hello world")
+
+	want := "MESSAGE=hello world\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeExportField(safe) = %q, want %q", got, want)
+	}
+}
+
+func TestWriteExportFieldBinarySafeValue(t *testing.T) {
+	var buf strings.Builder
+	value := "hello\nworld"
+	writeExportField(&buf, "MESSAGE", value)
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "MESSAGE\n") {
+		t.Fatalf("writeExportField(binary) = %q, want prefix %q", out, "MESSAGE\n")
+	}
+	if !strings.HasSuffix(out, value+"\n") {
+		t.Fatalf("writeExportField(binary) = %q, want suffix %q", out, value+"\n")
+	}
+}
+
+// TestFlushRetriesBatchAfterUploadFailure is a regression test for the
+// bug fixed in 0da5845: a failed upload must leave jr.pending intact so
+// the batch is retried, not silently dropped.
+func TestFlushRetriesBatchAfterUploadFailure(t *testing.T) {
+	var mu sync.Mutex
+	var requestBodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requestBodies = append(requestBodies, string(body))
+		attempt := len(requestBodies)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	jr, err := NewJournalRemote(JournalRemoteConfig{URL: server.URL, BatchSize: 100})
+	if err != nil {
+		t.Fatalf("NewJournalRemote: unexpected error: %v", err)
+	}
+
+	if err := jr.PublishEntry(&sdjournal.JournalEntry{Cursor: "a"}); err != nil {
+		t.Fatalf("PublishEntry: unexpected error: %v", err)
+	}
+
+	if err := jr.Flush(); err == nil {
+		t.Fatalf("Flush: expected error from the first (failing) upload, got none")
+	}
+	if len(jr.pending) != 1 {
+		t.Fatalf("pending = %d entries after a failed upload, want 1 (batch must be retried, not dropped)", len(jr.pending))
+	}
+
+	if err := jr.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error on retry: %v", err)
+	}
+	if len(jr.pending) != 0 {
+		t.Fatalf("pending = %d entries after a successful upload, want 0", len(jr.pending))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requestBodies) != 2 {
+		t.Fatalf("server received %d requests, want 2 (one failed, one retry)", len(requestBodies))
+	}
+	if requestBodies[0] != requestBodies[1] {
+		t.Fatalf("retry body differs from the original failed request:\nfirst:  %q\nsecond: %q", requestBodies[0], requestBodies[1])
+	}
+	if !strings.Contains(requestBodies[1], "__CURSOR=a") {
+		t.Fatalf("retried body %q does not contain the original entry", requestBodies[1])
+	}
+}
+
+// TestPublishEntryDropsOldestOncePendingExceedsMaxPending is a
+// regression test for unbounded memory growth during a sustained
+// outage: once pending reaches max_pending, PublishEntry must drop the
+// oldest entries rather than growing pending without limit.
+func TestPublishEntryDropsOldestOncePendingExceedsMaxPending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	jr, err := NewJournalRemote(JournalRemoteConfig{URL: server.URL, BatchSize: 100, MaxPending: 3})
+	if err != nil {
+		t.Fatalf("NewJournalRemote: unexpected error: %v", err)
+	}
+
+	for _, cursor := range []string{"a", "b", "c", "d", "e"} {
+		if err := jr.PublishEntry(&sdjournal.JournalEntry{Cursor: cursor}); err != nil {
+			t.Fatalf("PublishEntry(%q): unexpected error (batch_size not yet reached, Flush shouldn't run): %v", cursor, err)
+		}
+	}
+
+	if len(jr.pending) != 3 {
+		t.Fatalf("pending = %d entries, want 3 (bounded by max_pending)", len(jr.pending))
+	}
+
+	var gotCursors []string
+	for _, e := range jr.pending {
+		gotCursors = append(gotCursors, e.Cursor)
+	}
+	want := []string{"c", "d", "e"}
+	if strings.Join(gotCursors, ",") != strings.Join(want, ",") {
+		t.Fatalf("pending cursors = %v, want %v (oldest dropped first)", gotCursors, want)
+	}
+}